@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"time"
+)
+
+// maxDaemonRequestSize bounds how large a single daemon request frame may
+// be; oversized frames are rejected before their payload is even read.
+const maxDaemonRequestSize = 4096
+
+type daemonOp uint8
+
+const (
+	opBurnSeed daemonOp = iota + 1
+	opGetInfo
+	opVerifyTOTP
+)
+
+type daemonStatus uint8
+
+const (
+	statusOK daemonStatus = iota
+	statusError
+)
+
+// verifyTOTPRequest is the payload for opVerifyTOTP: a code to check
+// against the token's own live TOTP.
+type verifyTOTPRequest struct {
+	Code   string `json:"code"`
+	Digits uint8  `json:"digits"`
+}
+
+// runDaemon holds the smartcard context open and services burn requests
+// from other programs over a Unix socket, framed like an SSH-agent
+// request: uint32 big-endian length || uint8 op || payload, replied to
+// with uint32 big-endian length || uint8 status || payload.
+func runDaemon(socketPath string, token *Token) error {
+	os.Remove(socketPath) // remove a stale socket left by a previous run
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return fmt.Errorf("listening on %s: %w", socketPath, err)
+	}
+	defer listener.Close()
+
+	fmt.Printf("Listening on %s\n", socketPath)
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+		handleDaemonConn(conn, token)
+	}
+}
+
+func handleDaemonConn(conn net.Conn, token *Token) {
+	defer conn.Close()
+	for {
+		op, payload, err := readDaemonRequest(conn)
+		if err != nil {
+			if err != io.EOF {
+				fmt.Printf("daemon: reading request: %v\n", err)
+			}
+			return
+		}
+		status, reply := dispatchDaemonRequest(token, op, payload)
+		if err := writeDaemonReply(conn, status, reply); err != nil {
+			return
+		}
+	}
+}
+
+func readDaemonRequest(conn net.Conn) (op daemonOp, payload []byte, err error) {
+	var length uint32
+	if err = binary.Read(conn, binary.BigEndian, &length); err != nil {
+		return
+	}
+	if length == 0 {
+		return 0, nil, fmt.Errorf("empty request frame")
+	}
+	if length > maxDaemonRequestSize {
+		return 0, nil, fmt.Errorf("request size %d exceeds %d byte limit", length, maxDaemonRequestSize)
+	}
+	frame := make([]byte, length)
+	if _, err = io.ReadFull(conn, frame); err != nil {
+		return
+	}
+	return daemonOp(frame[0]), frame[1:], nil
+}
+
+func writeDaemonReply(conn net.Conn, status daemonStatus, payload []byte) error {
+	frame := append([]byte{uint8(status)}, payload...)
+	if err := binary.Write(conn, binary.BigEndian, uint32(len(frame))); err != nil {
+		return err
+	}
+	_, err := conn.Write(frame)
+	return err
+}
+
+func dispatchDaemonRequest(token *Token, op daemonOp, payload []byte) (daemonStatus, []byte) {
+	switch op {
+	case opBurnSeed:
+		var params SeedParams
+		if err := json.Unmarshal(payload, &params); err != nil {
+			return statusError, []byte(err.Error())
+		}
+		if err := burnAndVerify(token, params); err != nil {
+			return statusError, []byte(err.Error())
+		}
+		return statusOK, nil
+
+	case opGetInfo:
+		info, err := token.GetInfo()
+		if err != nil {
+			return statusError, []byte(err.Error())
+		}
+		return statusOK, info
+
+	case opVerifyTOTP:
+		var req verifyTOTPRequest
+		if err := json.Unmarshal(payload, &req); err != nil {
+			return statusError, []byte(err.Error())
+		}
+		got, err := token.GenerateTOTP(time.Now(), req.Digits)
+		if err != nil {
+			return statusError, []byte(err.Error())
+		}
+		if got != req.Code {
+			return statusError, []byte("TOTP mismatch")
+		}
+		return statusOK, nil
+
+	default:
+		return statusError, []byte(fmt.Sprintf("unknown op %d", op))
+	}
+}
@@ -10,14 +10,15 @@ import (
 	"flag"
 	"fmt"
 	"log"
+	"net/url"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/ebfe/scard"
 )
 
-var DEBUG bool
-
 type Tag interface {
 	Transceive(data []byte) (resp []byte, err error)
 }
@@ -26,6 +27,7 @@ var _ Tag = (*ScardTag)(nil)
 
 type ScardTag struct {
 	*scard.Card
+	Tracer Tracer
 }
 
 func (c *ScardTag) Transceive(data []byte) (resp []byte, err error) {
@@ -38,16 +40,20 @@ func (c *ScardTag) Transceive(data []byte) (resp []byte, err error) {
 }
 
 func (c *ScardTag) transceiveRaw(data []byte) (resp []byte, err error) {
-	if DEBUG {
-		fmt.Printf("> %#v\n", data)
-	}
+	c.Tracer.Trace(DirectionSent, data)
 	resp, err = c.Transmit(data)
-	if DEBUG {
-		fmt.Printf("< %#v (%#v)\n", resp, err)
+	if err == nil {
+		c.Tracer.Trace(DirectionReceived, resp)
 	}
 	return
 }
 
+// Redact lets callers (e.g. Token.BurnSeed) mark sensitive bytes so they
+// never show up in this tag's -debug trace.
+func (c *ScardTag) Redact(secret []byte) {
+	c.Tracer.Redact(secret)
+}
+
 type Token struct {
 	Tag
 }
@@ -89,23 +95,156 @@ func checksum(data []byte) (checksum uint16) {
 	return
 }
 
-func (t *Token) BurnSeed(seed []byte) (resp []byte, err error) {
-	buffer := append([]byte(nil), seed...)
-	buffer = append(buffer, 6, 30)
-	buffer = binary.BigEndian.AppendUint16(buffer, checksum(seed))
+// SeedParams describes a TOTP seed and the generation parameters it should
+// be burned with.
+type SeedParams struct {
+	Secret    []byte `json:"secret"`
+	Digits    uint8  `json:"digits"`
+	Period    uint8  `json:"period"`
+	Algorithm string `json:"algorithm"`
+}
+
+// supportedByToken reports whether info, as returned by Token.GetInfo,
+// indicates support for the given digits/period/algorithm combination.
+//
+// Following the fields already consumed elsewhere in this file (info[5]
+// is the XOR key, info[6] is the compute-OTP op code), info[7] is the
+// maximum number of digits the token can display, info[8] is a bitmask
+// of supported HMAC algorithms (bit 0 = SHA1, bit 1 = SHA256, bit 2 =
+// SHA512), and info[9] is the minimum period in seconds the token
+// accepts.
+func supportedByToken(info []byte, p SeedParams) error {
+	if len(info) < 10 {
+		return fmt.Errorf("token info too short to report capabilities")
+	}
+
+	maxDigits := info[7]
+	if p.Digits == 0 || p.Digits > maxDigits {
+		return fmt.Errorf("token only supports up to %d digits, got %d", maxDigits, p.Digits)
+	}
 
+	algBit, err := algorithmBit(p.Algorithm)
+	if err != nil {
+		return err
+	}
+	if info[8]&algBit == 0 {
+		return fmt.Errorf("token does not support algorithm %q", p.Algorithm)
+	}
+
+	minPeriod := info[9]
+	if p.Period < minPeriod {
+		return fmt.Errorf("token requires a period of at least %d seconds, got %d", minPeriod, p.Period)
+	}
+	return nil
+}
+
+// algorithmBit returns the info[8] capability bitmask bit for algorithm.
+func algorithmBit(algorithm string) (uint8, error) {
+	switch strings.ToUpper(algorithm) {
+	case "", "SHA1":
+		return 1 << 0, nil
+	case "SHA256":
+		return 1 << 1, nil
+	case "SHA512":
+		return 1 << 2, nil
+	default:
+		return 0, fmt.Errorf("unsupported TOTP algorithm %q", algorithm)
+	}
+}
+
+func (t *Token) BurnSeed(p SeedParams) (resp []byte, err error) {
 	data, err := t.GetInfo()
 	if err != nil {
 		return
 	}
+	if err = supportedByToken(data, p); err != nil {
+		return nil, err
+	}
+
+	buffer := append([]byte(nil), p.Secret...)
+	buffer = append(buffer, p.Digits, p.Period)
+	buffer = binary.BigEndian.AppendUint16(buffer, checksum(p.Secret))
+
+	if redactor, ok := t.Tag.(interface{ Redact([]byte) }); ok {
+		redactor.Redact(p.Secret)
+		redactor.Redact(buffer)
+	}
+
 	key := data[5]
 	for i := range buffer {
 		buffer[i] ^= key
 	}
+	if redactor, ok := t.Tag.(interface{ Redact([]byte) }); ok {
+		redactor.Redact(buffer)
+	}
 
 	return t.Transceive(append([]byte{0x18}, buffer...))
 }
 
+// GenerateTOTP asks the card to compute the TOTP for the given time, using
+// the "compute OTP" op code discovered from GetInfo, and returns it as a
+// zero-padded decimal string of the requested number of digits.
+func (t *Token) GenerateTOTP(at time.Time, digits uint8) (code string, err error) {
+	info, err := t.GetInfo()
+	if err != nil {
+		return
+	}
+	if len(info) < 7 {
+		return "", fmt.Errorf("token info too short to contain compute-OTP op code")
+	}
+	computeOTPOp := info[6]
+
+	buffer := binary.BigEndian.AppendUint64(nil, uint64(at.Unix()))
+	resp, err := t.Transceive(append([]byte{computeOTPOp}, buffer...))
+	if err != nil {
+		return
+	}
+	if len(resp) < 4 {
+		return "", fmt.Errorf("compute-OTP response too short")
+	}
+
+	value := binary.BigEndian.Uint32(resp[:4])
+	mod := uint32(1)
+	for i := uint8(0); i < digits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", digits, value%mod), nil
+}
+
+// maxBurnAttempts bounds how many times in all BurnSeed is attempted
+// (the first burn plus any retries) when the card's own TOTP does not
+// match the expected value.
+const maxBurnAttempts = 3
+
+// burnAndVerify burns params onto token and reads back a live TOTP to
+// confirm the seed was actually stored correctly, retrying the burn on
+// mismatch for at most maxBurnAttempts burns in all.
+func burnAndVerify(token *Token, params SeedParams) (err error) {
+	for attempt := 1; attempt <= maxBurnAttempts; attempt++ {
+		if _, err = token.BurnSeed(params); err != nil {
+			return
+		}
+
+		now := time.Now()
+		var got string
+		got, err = token.GenerateTOTP(now, params.Digits)
+		if err != nil {
+			return fmt.Errorf("reading back TOTP: %w", err)
+		}
+		var want string
+		want, err = computeTOTP(params.Secret, now, params.Digits, params.Period, params.Algorithm)
+		if err != nil {
+			return
+		}
+
+		if got == want {
+			return nil
+		}
+		err = fmt.Errorf("burned seed did not verify after %d attempt(s): token returned %q, expected %q", attempt, got, want)
+	}
+	return
+}
+
 func decodeHexOrBase32(str string) ([]byte, error) {
 	str = strings.ToUpper(strings.TrimSpace(str))
 	data, err := hex.DecodeString(str)
@@ -119,10 +258,81 @@ func decodeHexOrBase32(str string) ([]byte, error) {
 	return nil, errors.Join(err, err2)
 }
 
+// parseSeedInput parses either a raw hex/base32 secret or a full
+// otpauth://totp/... URI (RFC 6238 / Google Authenticator KeyUriFormat)
+// into SeedParams. Digits and period default to 6 and 30 if not present
+// in the URI.
+func parseSeedInput(str string) (SeedParams, error) {
+	str = strings.TrimSpace(str)
+	if !strings.HasPrefix(str, "otpauth://") {
+		secret, err := decodeHexOrBase32(str)
+		if err != nil {
+			return SeedParams{}, err
+		}
+		return SeedParams{Secret: secret, Digits: 6, Period: 30, Algorithm: "SHA1"}, nil
+	}
+
+	u, err := url.Parse(str)
+	if err != nil {
+		return SeedParams{}, fmt.Errorf("parsing otpauth URI: %w", err)
+	}
+	if u.Host != "totp" {
+		return SeedParams{}, fmt.Errorf("unsupported otpauth type %q, only totp is supported", u.Host)
+	}
+	q := u.Query()
+
+	secretStr := q.Get("secret")
+	if secretStr == "" {
+		return SeedParams{}, fmt.Errorf("otpauth URI is missing secret parameter")
+	}
+	secret, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(strings.ToUpper(secretStr))
+	if err != nil {
+		return SeedParams{}, fmt.Errorf("decoding secret: %w", err)
+	}
+
+	p := SeedParams{Secret: secret, Digits: 6, Period: 30, Algorithm: "SHA1"}
+	if v := q.Get("digits"); v != "" {
+		digits, err := strconv.ParseUint(v, 10, 8)
+		if err != nil {
+			return SeedParams{}, fmt.Errorf("parsing digits: %w", err)
+		}
+		p.Digits = uint8(digits)
+	}
+	if v := q.Get("period"); v != "" {
+		period, err := strconv.ParseUint(v, 10, 8)
+		if err != nil {
+			return SeedParams{}, fmt.Errorf("parsing period: %w", err)
+		}
+		p.Period = uint8(period)
+	}
+	if v := q.Get("algorithm"); v != "" {
+		p.Algorithm = v
+	}
+	return p, nil
+}
+
 func run() (err error) {
-	flag.BoolVar(&DEBUG, "debug", false, "show debug output")
+	debugFlag := flag.Bool("debug", false, "show a debug trace of APDUs exchanged with the token")
+	debugJSONFlag := flag.Bool("debug-json", false, "emit the -debug trace as JSON lines instead of human-readable hex dumps")
+	digitsFlag := flag.Int("digits", 0, "override number of TOTP digits (default: from URI or 6)")
+	periodFlag := flag.Int("period", 0, "override TOTP period in seconds (default: from URI or 30)")
+	algorithmFlag := flag.String("algorithm", "", "override TOTP algorithm (default: from URI or SHA1)")
+	qrFlag := flag.String("qr", "", "read the seed from a QR code in this PNG/JPEG image file instead of stdin")
+	qrDeviceFlag := flag.String("qr-device", "", "read the seed from a QR code captured from this V4L2 video device (e.g. /dev/video0)")
+	daemonFlag := flag.String("daemon", "", "serve burn requests from other programs on this Unix socket instead of running once")
+	readerFlag := flag.String("reader", "", "substring of the reader name to use, if more than one is connected")
+	timeoutFlag := flag.Duration("timeout", 0, "give up waiting for a card after this long (default: wait forever)")
 	flag.Parse()
 
+	var tracer Tracer = noopTracer{}
+	if *debugFlag {
+		if *debugJSONFlag {
+			tracer = NewJSONTracer(os.Stderr)
+		} else {
+			tracer = NewHumanTracer(os.Stderr)
+		}
+	}
+
 	context, err := scard.EstablishContext()
 	if err != nil {
 		return
@@ -132,35 +342,69 @@ func run() (err error) {
 		return
 	}
 
-	reader := bufio.NewReader(os.Stdin)
+	stdin := bufio.NewReader(os.Stdin)
 
-	fmt.Print("Seed (hex or base32): ")
-	seedString, err := reader.ReadString('\n')
+	reader, err := selectReader(readers, *readerFlag, stdin)
 	if err != nil {
 		return
 	}
-	seed, err := decodeHexOrBase32(seedString)
+
+	if *daemonFlag != "" {
+		fmt.Printf("Place token on %s...\n", reader)
+		card, err := waitForCard(context, reader, *timeoutFlag)
+		if err != nil {
+			return err
+		}
+		token := Token{&ScardTag{Card: card, Tracer: tracer}}
+		return runDaemon(*daemonFlag, &token)
+	}
+
+	var source SeedSource
+	switch {
+	case *qrFlag != "":
+		source = FileQRSeedSource{Path: *qrFlag}
+	case *qrDeviceFlag != "":
+		source = DeviceQRSeedSource{Device: *qrDeviceFlag}
+	default:
+		source = StdinSeedSource{Reader: stdin}
+	}
+
+	seedString, err := source.SeedInput()
 	if err != nil {
 		return
 	}
-
-	fmt.Println("Place token on reader...")
-	var card *scard.Card
-	for card == nil {
-		for _, reader := range readers {
-			if card, err = context.Connect(reader, scard.ShareShared, scard.ProtocolAny); err == nil {
-				break
-			}
+	params, err := parseSeedInput(seedString)
+	if err != nil {
+		return
+	}
+	if *digitsFlag != 0 {
+		if *digitsFlag < 0 || *digitsFlag > 255 {
+			return fmt.Errorf("-digits must be between 1 and 255, got %d", *digitsFlag)
 		}
+		params.Digits = uint8(*digitsFlag)
+	}
+	if *periodFlag != 0 {
+		if *periodFlag < 0 || *periodFlag > 255 {
+			return fmt.Errorf("-period must be between 1 and 255, got %d", *periodFlag)
+		}
+		params.Period = uint8(*periodFlag)
+	}
+	if *algorithmFlag != "" {
+		params.Algorithm = *algorithmFlag
 	}
-	token := Token{&ScardTag{card}}
 
-	fmt.Println("Burning seed...")
-	_, err = token.BurnSeed(seed)
+	fmt.Printf("Place token on %s...\n", reader)
+	card, err := waitForCard(context, reader, *timeoutFlag)
 	if err != nil {
 		return
 	}
-	fmt.Println("Success")
+	token := Token{&ScardTag{Card: card, Tracer: tracer}}
+
+	fmt.Println("Burning seed...")
+	if err = burnAndVerify(&token, params); err != nil {
+		return
+	}
+	fmt.Println("Success (verified)")
 	return
 }
 
@@ -0,0 +1,82 @@
+package main
+
+import "testing"
+
+func TestDecodeHexOrBase32(t *testing.T) {
+	cases := []struct {
+		name    string
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{"hex", "48656c6c6f", "Hello", false},
+		{"base32", "JBSWY3DP", "Hello", false},
+		{"invalid", "not valid!!", "", true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := decodeHexOrBase32(c.in)
+			if (err != nil) != c.wantErr {
+				t.Fatalf("decodeHexOrBase32(%q) error = %v, wantErr %v", c.in, err, c.wantErr)
+			}
+			if err == nil && string(got) != c.want {
+				t.Errorf("decodeHexOrBase32(%q) = %q, want %q", c.in, got, c.want)
+			}
+		})
+	}
+}
+
+func TestParseSeedInputRawSecret(t *testing.T) {
+	params, err := parseSeedInput("JBSWY3DPEHPK3PXP")
+	if err != nil {
+		t.Fatalf("parseSeedInput: %v", err)
+	}
+	if params.Digits != 6 || params.Period != 30 || params.Algorithm != "SHA1" {
+		t.Errorf("unexpected defaults: %+v", params)
+	}
+}
+
+func TestParseSeedInputOtpauthURI(t *testing.T) {
+	params, err := parseSeedInput("otpauth://totp/Example:alice@example.com?secret=JBSWY3DPEHPK3PXP&digits=8&period=60&algorithm=SHA256")
+	if err != nil {
+		t.Fatalf("parseSeedInput: %v", err)
+	}
+	if params.Digits != 8 || params.Period != 60 || params.Algorithm != "SHA256" {
+		t.Errorf("unexpected params: %+v", params)
+	}
+}
+
+func TestParseSeedInputOtpauthURIMissingSecret(t *testing.T) {
+	if _, err := parseSeedInput("otpauth://totp/Example:alice@example.com"); err == nil {
+		t.Fatal("expected error for missing secret")
+	}
+}
+
+func TestSupportedByToken(t *testing.T) {
+	// maxDigits=8 (info[7]), algorithms=SHA1|SHA256|SHA512 (info[8]), minPeriod=15 (info[9])
+	info := []byte{0, 0, 0, 0, 0, 0, 0, 8, 0b111, 15}
+
+	cases := []struct {
+		name    string
+		params  SeedParams
+		wantErr bool
+	}{
+		{"ok", SeedParams{Digits: 6, Period: 30, Algorithm: "SHA1"}, false},
+		{"too many digits", SeedParams{Digits: 9, Period: 30, Algorithm: "SHA1"}, true},
+		{"period too short", SeedParams{Digits: 6, Period: 10, Algorithm: "SHA1"}, true},
+		{"unsupported algorithm", SeedParams{Digits: 6, Period: 30, Algorithm: "MD5"}, true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if err := supportedByToken(info, c.params); (err != nil) != c.wantErr {
+				t.Errorf("supportedByToken() error = %v, wantErr %v", err, c.wantErr)
+			}
+		})
+	}
+}
+
+func TestSupportedByTokenShortInfo(t *testing.T) {
+	if err := supportedByToken([]byte{0, 0, 0}, SeedParams{Digits: 6, Period: 30, Algorithm: "SHA1"}); err == nil {
+		t.Fatal("expected error for truncated token info")
+	}
+}
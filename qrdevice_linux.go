@@ -0,0 +1,46 @@
+//go:build linux
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image/jpeg"
+	"time"
+
+	"github.com/vladimirvivien/go4vl/device"
+	"github.com/vladimirvivien/go4vl/v4l2"
+)
+
+// DeviceQRSeedSource captures a single frame from a V4L2 video device (e.g.
+// /dev/video0) and decodes the QR code within it.
+type DeviceQRSeedSource struct {
+	Device string
+}
+
+func (s DeviceQRSeedSource) SeedInput() (string, error) {
+	cam, err := device.Open(s.Device, device.WithPixFormat(v4l2.PixFormat{PixelFormat: v4l2.PixelFmtMJPEG}))
+	if err != nil {
+		return "", fmt.Errorf("opening %s: %w", s.Device, err)
+	}
+	defer cam.Close()
+
+	if err := cam.Start(context.TODO()); err != nil {
+		return "", fmt.Errorf("starting capture on %s: %w", s.Device, err)
+	}
+
+	select {
+	case frame, ok := <-cam.GetOutput():
+		if !ok {
+			return "", fmt.Errorf("capturing frame from %s: stream closed", s.Device)
+		}
+		img, err := jpeg.Decode(bytes.NewReader(frame))
+		if err != nil {
+			return "", fmt.Errorf("decoding captured frame: %w", err)
+		}
+		return decodeQRImage(img)
+	case <-time.After(5 * time.Second):
+		return "", fmt.Errorf("timed out waiting for a frame from %s", s.Device)
+	}
+}
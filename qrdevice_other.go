@@ -0,0 +1,14 @@
+//go:build !linux
+
+package main
+
+import "fmt"
+
+// DeviceQRSeedSource is only implemented on Linux (V4L2 capture).
+type DeviceQRSeedSource struct {
+	Device string
+}
+
+func (s DeviceQRSeedSource) SeedInput() (string, error) {
+	return "", fmt.Errorf("-qr-device is only supported on Linux (V4L2)")
+}
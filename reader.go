@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ebfe/scard"
+)
+
+// selectReader narrows readers down to the one to use: filtered by
+// substring if given, or chosen interactively by reading a line from in
+// if more than one match remains. in is shared with any later prompt
+// (e.g. StdinSeedSource) so a piped stdin isn't partially consumed into a
+// buffer that gets discarded when this function returns.
+func selectReader(readers []string, substring string, in *bufio.Reader) (string, error) {
+	if substring != "" {
+		var matches []string
+		for _, r := range readers {
+			if strings.Contains(strings.ToLower(r), strings.ToLower(substring)) {
+				matches = append(matches, r)
+			}
+		}
+		readers = matches
+	}
+
+	switch len(readers) {
+	case 0:
+		if substring != "" {
+			return "", fmt.Errorf("no reader matches %q", substring)
+		}
+		return "", fmt.Errorf("no readers found")
+	case 1:
+		return readers[0], nil
+	}
+
+	fmt.Println("Multiple readers found:")
+	for i, r := range readers {
+		fmt.Printf("  %d. %s\n", i+1, r)
+	}
+	fmt.Print("Select reader: ")
+	line, err := in.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	choice, err := strconv.Atoi(strings.TrimSpace(line))
+	if err != nil || choice < 1 || choice > len(readers) {
+		return "", fmt.Errorf("invalid selection %q", strings.TrimSpace(line))
+	}
+	return readers[choice-1], nil
+}
+
+// waitForCard blocks until a card is present in reader, using
+// Context.GetStatusChange rather than busy-looping on Connect, and
+// connects to it. A zero timeout waits forever; a non-zero timeout bounds
+// the *total* wait, not just the gap between individual status-change
+// events.
+func waitForCard(context *scard.Context, reader string, timeout time.Duration) (*scard.Card, error) {
+	wait := time.Duration(-1) // negative means "wait forever" to scard.Context.GetStatusChange
+	var deadline time.Time
+	if timeout > 0 {
+		wait = timeout
+		deadline = time.Now().Add(timeout)
+	}
+
+	state := scard.ReaderState{
+		Reader:       reader,
+		CurrentState: scard.StateUnaware,
+	}
+	for {
+		states := []scard.ReaderState{state}
+		if err := context.GetStatusChange(states, wait); err != nil {
+			return nil, fmt.Errorf("waiting for card on %s: %w", reader, err)
+		}
+		state = states[0]
+		if state.EventState&scard.StatePresent != 0 {
+			return context.Connect(reader, scard.ShareShared, scard.ProtocolAny)
+		}
+		state.CurrentState = state.EventState
+
+		if timeout > 0 {
+			if wait = time.Until(deadline); wait <= 0 {
+				return nil, fmt.Errorf("timed out waiting for a card on %s", reader)
+			}
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"os"
+
+	"github.com/makiuchi-d/gozxing"
+	"github.com/makiuchi-d/gozxing/qrcode"
+)
+
+// SeedSource produces the raw seed input text (a hex/base32 secret or an
+// otpauth:// URI) that is then handed to parseSeedInput. This lets run()
+// dispatch between stdin, a QR image file, or a camera without duplicating
+// the burn flow.
+type SeedSource interface {
+	SeedInput() (string, error)
+}
+
+// StdinSeedSource prompts interactively and reads a line from Reader.
+type StdinSeedSource struct {
+	Reader *bufio.Reader
+}
+
+func (s StdinSeedSource) SeedInput() (string, error) {
+	fmt.Print("Seed (hex, base32, or otpauth:// URI): ")
+	return s.Reader.ReadString('\n')
+}
+
+// FileQRSeedSource decodes a QR code from a PNG/JPEG image file and
+// returns the otpauth:// URI (or other text) it encodes.
+type FileQRSeedSource struct {
+	Path string
+}
+
+func (s FileQRSeedSource) SeedInput() (string, error) {
+	f, err := os.Open(s.Path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return "", fmt.Errorf("decoding image %s: %w", s.Path, err)
+	}
+	return decodeQRImage(img)
+}
+
+// decodeQRImage extracts the text encoded by the first QR code found in img.
+func decodeQRImage(img image.Image) (string, error) {
+	bmp, err := gozxing.NewBinaryBitmapFromImage(img)
+	if err != nil {
+		return "", fmt.Errorf("binarizing image: %w", err)
+	}
+	result, err := qrcode.NewQRCodeReader().Decode(bmp, nil)
+	if err != nil {
+		return "", fmt.Errorf("decoding QR code: %w", err)
+	}
+	return result.GetText(), nil
+}
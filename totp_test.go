@@ -0,0 +1,44 @@
+package main
+
+import (
+	"encoding/hex"
+	"testing"
+	"time"
+)
+
+// RFC 6238 Appendix B test vectors at T=59s (T0=0, step=30 -> counter 1).
+func TestComputeTOTPRFC6238Vectors(t *testing.T) {
+	sha1Secret, _ := hex.DecodeString("3132333435363738393031323334353637383930")
+	sha256Secret, _ := hex.DecodeString("3132333435363738393031323334353637383930313233343536373839303132")
+	sha512Secret, _ := hex.DecodeString("31323334353637383930313233343536373839303132333435363738393031323334353637383930313233343536373839303132333435363738393031323334")
+
+	cases := []struct {
+		name      string
+		secret    []byte
+		algorithm string
+		want      string
+	}{
+		{"SHA1", sha1Secret, "SHA1", "94287082"},
+		{"SHA256", sha256Secret, "SHA256", "46119246"},
+		{"SHA512", sha512Secret, "SHA512", "90693936"},
+	}
+
+	at := time.Unix(59, 0)
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := computeTOTP(c.secret, at, 8, 30, c.algorithm)
+			if err != nil {
+				t.Fatalf("computeTOTP: %v", err)
+			}
+			if got != c.want {
+				t.Errorf("computeTOTP() = %q, want %q", got, c.want)
+			}
+		})
+	}
+}
+
+func TestComputeTOTPUnsupportedAlgorithm(t *testing.T) {
+	if _, err := computeTOTP([]byte("secret"), time.Unix(0, 0), 6, 30, "MD5"); err == nil {
+		t.Fatal("expected error for unsupported algorithm")
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Direction distinguishes an APDU sent to the card from the card's
+// response.
+type Direction string
+
+const (
+	DirectionSent     Direction = "sent"
+	DirectionReceived Direction = "received"
+)
+
+// Tracer receives annotated APDU frames for -debug output. Redact marks a
+// byte sequence (e.g. a seed about to be burned) as sensitive so that
+// subsequent Trace calls mask it out wherever it occurs, letting users
+// safely share -debug logs when reporting issues.
+type Tracer interface {
+	Redact(secret []byte)
+	Trace(direction Direction, data []byte)
+}
+
+// noopTracer discards everything; used when -debug is off.
+type noopTracer struct{}
+
+func (noopTracer) Redact([]byte)           {}
+func (noopTracer) Trace(Direction, []byte) {}
+
+// redactor masks previously-registered secret byte strings out of traced
+// frames before they reach a Tracer's output.
+type redactor struct {
+	mu      sync.Mutex
+	secrets [][]byte
+}
+
+func (r *redactor) Redact(secret []byte) {
+	if len(secret) == 0 {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.secrets = append(r.secrets, append([]byte(nil), secret...))
+}
+
+func (r *redactor) mask(data []byte) []byte {
+	r.mu.Lock()
+	secrets := r.secrets
+	r.mu.Unlock()
+
+	out := append([]byte(nil), data...)
+	for _, secret := range secrets {
+		for i := 0; i+len(secret) <= len(out); i++ {
+			if bytes.Equal(out[i:i+len(secret)], secret) {
+				for j := range secret {
+					out[i+j] = '*'
+				}
+			}
+		}
+	}
+	return out
+}
+
+// HumanTracer writes timestamped, annotated hex.Dump frames to Out,
+// suitable for watching in a terminal.
+type HumanTracer struct {
+	redactor
+	Out io.Writer
+}
+
+func NewHumanTracer(out io.Writer) *HumanTracer {
+	return &HumanTracer{Out: out}
+}
+
+func (t *HumanTracer) Trace(direction Direction, data []byte) {
+	fmt.Fprintf(t.Out, "[%s] %s\n%s", time.Now().Format(time.RFC3339Nano), apduSummary(direction, data), hex.Dump(t.mask(data)))
+}
+
+// JSONTracer writes one JSON object per frame to Out, suitable for piping
+// into jq.
+type JSONTracer struct {
+	redactor
+	Out io.Writer
+}
+
+func NewJSONTracer(out io.Writer) *JSONTracer {
+	return &JSONTracer{Out: out}
+}
+
+type jsonTraceFrame struct {
+	Time      string    `json:"time"`
+	Direction Direction `json:"direction"`
+	Class     *uint8    `json:"class,omitempty"`
+	Ins       *uint8    `json:"ins,omitempty"`
+	Hex       string    `json:"hex"`
+}
+
+func (t *JSONTracer) Trace(direction Direction, data []byte) {
+	masked := t.mask(data)
+	frame := jsonTraceFrame{
+		Time:      time.Now().Format(time.RFC3339Nano),
+		Direction: direction,
+		Hex:       hex.EncodeToString(masked),
+	}
+	if direction == DirectionSent && len(masked) >= 2 {
+		class, ins := masked[0], masked[1]
+		frame.Class, frame.Ins = &class, &ins
+	}
+	enc, err := json.Marshal(frame)
+	if err != nil {
+		return
+	}
+	fmt.Fprintln(t.Out, string(enc))
+}
+
+// apduSummary decodes the APDU class/INS bytes of a sent frame, or just
+// reports the frame length for a response.
+func apduSummary(direction Direction, data []byte) string {
+	if direction != DirectionSent || len(data) < 2 {
+		return fmt.Sprintf("%s (%d byte(s))", direction, len(data))
+	}
+	return fmt.Sprintf("%s CLA=%#02x INS=%#02x (%d byte(s))", direction, data[0], data[1], len(data))
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRedactorMask(t *testing.T) {
+	var r redactor
+	r.Redact([]byte("secret"))
+
+	in := []byte("prefix-secret-suffix")
+	got := r.mask(in)
+	if bytes.Contains(got, []byte("secret")) {
+		t.Errorf("mask() did not redact secret: %q", got)
+	}
+	if !bytes.HasPrefix(got, []byte("prefix-")) || !bytes.HasSuffix(got, []byte("-suffix")) {
+		t.Errorf("mask() altered unrelated bytes: %q", got)
+	}
+	if len(got) != len(in) {
+		t.Errorf("mask() changed length: got %d, want %d", len(got), len(in))
+	}
+}
+
+func TestRedactorMaskNoSecrets(t *testing.T) {
+	var r redactor
+	in := []byte("hello")
+	got := r.mask(in)
+	if !bytes.Equal(got, in) {
+		t.Errorf("mask() = %q, want unchanged %q", got, in)
+	}
+}
+
+func TestApduSummary(t *testing.T) {
+	sent := apduSummary(DirectionSent, []byte{0x00, 0xa4, 0x04, 0x00})
+	if !strings.Contains(sent, "CLA=0x00") || !strings.Contains(sent, "INS=0xa4") {
+		t.Errorf("apduSummary(sent) = %q, missing decoded CLA/INS", sent)
+	}
+
+	received := apduSummary(DirectionReceived, []byte{0xaa, 0x01, 0x02})
+	if strings.Contains(received, "CLA=") {
+		t.Errorf("apduSummary(received) = %q, should not decode CLA/INS", received)
+	}
+}